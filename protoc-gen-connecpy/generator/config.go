@@ -1,7 +1,8 @@
 package generator
 
 import (
-	"strings"
+	"flag"
+	"fmt"
 )
 
 // Naming is the naming convention to use for generated symbols.
@@ -38,44 +39,55 @@ type Config struct {
 	// TransportAPI enables generation of experimental Transport API support.
 	// This includes Protocol types, gRPC wrappers, and factory functions.
 	TransportAPI bool
+
+	// OpenAPI enables emitting an OpenAPI v3 description of every service
+	// alongside the generated Python stubs.
+	OpenAPI bool
+
+	// TemplateDir, if set, is a directory of user-supplied `.tmpl` files
+	// that override the built-in ConnecpyTemplate. One output file is
+	// emitted per template found.
+	TemplateDir string
+
+	// SingleFile controls how multiple user templates are combined: when
+	// true, all rendered templates are concatenated into a single output
+	// file instead of one file per template.
+	SingleFile bool
+
+	// Stubs enables emitting a `.pyi` type stub file alongside the
+	// generated `_connecpy.py` module.
+	Stubs bool
 }
 
-func parseConfig(p string) Config {
-	// Proto parameters should always be treated as CSV to match Buf's pattern.
-	// There is no consistency on the items themselves but we use key=value.
-	parts := strings.Split(p, ",")
-	cfg := Config{}
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		key, value, ok := strings.Cut(part, "=")
-		if !ok {
-			continue
+// RegisterFlags registers c's fields onto flags, so a protogen.Options{}'s
+// ParamFunc (e.g. flags.Set) can populate c straight from the plugin
+// parameter string protoc/buf passes on the command line.
+func (c *Config) RegisterFlags(flags *flag.FlagSet) {
+	flags.Func("naming", "naming convention for generated symbols: pep or google", func(value string) error {
+		switch value {
+		case "pep":
+			c.Naming = NamingPEP
+		case "google":
+			c.Naming = NamingGoogle
+		default:
+			return fmt.Errorf("unknown naming %q, want pep or google", value)
 		}
-		key = strings.TrimSpace(key)
-		value = strings.TrimSpace(value)
-		switch key {
-		case "naming":
-			switch value {
-			case "pep":
-				cfg.Naming = NamingPEP
-			case "google":
-				cfg.Naming = NamingGoogle
-			}
-		case "imports":
-			switch value {
-			case "absolute":
-				cfg.Imports = ImportsAbsolute
-			case "relative":
-				cfg.Imports = ImportsRelative
-			}
-		case "transport_api":
-			switch value {
-			case "true", "1", "yes":
-				cfg.TransportAPI = true
-			case "false", "0", "no":
-				cfg.TransportAPI = false
-			}
+		return nil
+	})
+	flags.Func("imports", "import style for generated modules: absolute or relative", func(value string) error {
+		switch value {
+		case "absolute":
+			c.Imports = ImportsAbsolute
+		case "relative":
+			c.Imports = ImportsRelative
+		default:
+			return fmt.Errorf("unknown imports %q, want absolute or relative", value)
 		}
-	}
-	return cfg
+		return nil
+	})
+	flags.BoolVar(&c.TransportAPI, "transport_api", false, "generate experimental Transport API support")
+	flags.BoolVar(&c.OpenAPI, "openapi", false, "emit an OpenAPI v3 spec alongside the generated stubs")
+	flags.StringVar(&c.TemplateDir, "templates", "", "directory of user-supplied .tmpl files that override the built-in templates")
+	flags.BoolVar(&c.SingleFile, "single_file", false, "concatenate all user templates into a single output file instead of one per template")
+	flags.BoolVar(&c.Stubs, "stubs", false, "emit a .pyi type stub file alongside the generated module")
 }
@@ -2,92 +2,145 @@ package generator
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"path"
 	"slices"
 	"strings"
 	"unicode"
 
-	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/compiler/protogen"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/i2y/connecpy/v2/protoc-gen-connecpy/generator/openapi"
 )
 
-func Generate(r *plugin.CodeGeneratorRequest) *plugin.CodeGeneratorResponse {
-	resp := &plugin.CodeGeneratorResponse{}
+// Generate runs the connecpy code generator against every file gen was asked to generate.
+func Generate(gen *protogen.Plugin, conf Config) error {
+	gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) | uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)
+	gen.SupportedEditionsMinimum = descriptorpb.Edition_EDITION_PROTO3
+	gen.SupportedEditionsMaximum = descriptorpb.Edition_EDITION_2023
 
-	resp.SupportedFeatures = proto.Uint64(uint64(plugin.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) | uint64(plugin.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS))
-	resp.MinimumEdition = proto.Int32(int32(descriptorpb.Edition_EDITION_PROTO3))
-	resp.MaximumEdition = proto.Int32(int32(descriptorpb.Edition_EDITION_2023))
+	for _, file := range gen.Files {
+		if !file.Generate {
+			continue
+		}
+		// We don't generate any code for non-services
+		if len(file.Services) == 0 {
+			continue
+		}
 
-	conf := parseConfig(r.GetParameter())
+		if err := GenerateConnecpyFile(gen, file, conf); err != nil {
+			return fmt.Errorf("file[%s][generate]: %w", file.Desc.Path(), err)
+		}
 
-	files := r.GetFileToGenerate()
-	if len(files) == 0 {
-		resp.Error = proto.String("no files to generate")
-		return resp
-	}
+		if conf.Stubs {
+			if err := GenerateStubFile(gen, file, conf); err != nil {
+				return fmt.Errorf("file[%s][stubs]: %w", file.Desc.Path(), err)
+			}
+		}
 
-	fds := &descriptorpb.FileDescriptorSet{
-		File: r.GetProtoFile(),
+		if conf.OpenAPI {
+			if err := GenerateOpenAPIFile(gen, file); err != nil {
+				return fmt.Errorf("file[%s][openapi]: %w", file.Desc.Path(), err)
+			}
+		}
 	}
-	reg, err := protodesc.NewFiles(fds)
+
+	return nil
+}
+
+// GenerateConnecpyFile renders the `_connecpy.py` module for file and registers it with gen.
+func GenerateConnecpyFile(gen *protogen.Plugin, file *protogen.File, conf Config) error {
+	vars, err := connecpyTemplateVariables(file, conf)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	reg.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
-		if !slices.Contains(files, string(fd.Path())) {
-			return true
-		}
+	if conf.TemplateDir != "" {
+		return generateFromUserTemplates(gen, file, vars, conf)
+	}
 
-		// We don't generate any code for non-services
-		if fd.Services().Len() == 0 {
-			return true
-		}
+	var buf bytes.Buffer
+	if err := ConnecpyTemplate.Execute(&buf, vars); err != nil {
+		return err
+	}
 
-		connecpyFile, err := GenerateConnecpyFile(fd, conf)
-		if err != nil {
-			resp.Error = proto.String("File[" + fd.Path() + "][generate]: " + err.Error())
-			return false
-		}
-		resp.File = append(resp.File, connecpyFile)
-		return true
-	})
+	g := gen.NewGeneratedFile(connecpyFilename(file.Desc.Path()), file.GoImportPath)
+	g.P(buf.String())
+	return nil
+}
 
-	return resp
+func generateFromUserTemplates(gen *protogen.Plugin, file *protogen.File, vars ConnecpyTemplateVariables, conf Config) error {
+	templates, err := LoadTemplateSet(conf.TemplateDir)
+	if err != nil {
+		return err
+	}
+
+	rendered, err := templates.Render(vars, conf)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range rendered {
+		g := gen.NewGeneratedFile(f.Name, file.GoImportPath)
+		g.P(f.Content)
+	}
+	return nil
 }
 
-func GenerateConnecpyFile(fd protoreflect.FileDescriptor, conf Config) (*plugin.CodeGeneratorResponse_File, error) {
+func connecpyTemplateVariables(file *protogen.File, conf Config) (ConnecpyTemplateVariables, error) {
+	fd := file.Desc
 	filename := fd.Path()
 
-	fileNameWithoutSuffix := strings.TrimSuffix(filename, path.Ext(filename))
-	moduleName := strings.Join(strings.Split(fileNameWithoutSuffix, "/"), ".")
-
 	vars := ConnecpyTemplateVariables{
 		FileName:   filename,
-		ModuleName: moduleName,
+		ModuleName: moduleFromPath(filename),
 		Imports:    importStatements(fd, conf),
 	}
+	if conf.OpenAPI {
+		vars.OpenAPI = openAPIFilename(filename)
+	}
+
+	descriptorSetB64, err := descriptorSetBase64(fd)
+	if err != nil {
+		return vars, fmt.Errorf("encoding descriptor set: %w", err)
+	}
+	vars.DescriptorSetBase64 = descriptorSetB64
+
+	fileDeprecated := false
+	if fo, ok := fd.Options().(*descriptorpb.FileOptions); ok {
+		fileDeprecated = fo.GetDeprecated()
+	}
 
-	svcs := fd.Services()
 	packageName := string(fd.Package())
-	for i := 0; i < svcs.Len(); i++ {
-		svc := svcs.Get(i)
+	for _, svc := range file.Services {
+		svcDeprecated := fileDeprecated
+		if so, ok := svc.Desc.Options().(*descriptorpb.ServiceOptions); ok {
+			svcDeprecated = svcDeprecated || so.GetDeprecated()
+		}
+
 		connecpySvc := &ConnecpyService{
-			Name:     string(svc.Name()),
-			FullName: string(svc.FullName()),
-			Package:  packageName,
+			Name:               string(svc.Desc.Name()),
+			FullName:           string(svc.Desc.FullName()),
+			Package:            packageName,
+			Deprecated:         svcDeprecated,
+			DeprecationMessage: deprecationMessage(svc.Comments),
 		}
 
-		methods := svc.Methods()
-		for j := 0; j < methods.Len(); j++ {
-			method := methods.Get(j)
+		for _, method := range svc.Methods {
+			desc := method.Desc
+			methodDeprecated := svcDeprecated
+			if mo, ok := desc.Options().(*descriptorpb.MethodOptions); ok {
+				methodDeprecated = methodDeprecated || mo.GetDeprecated()
+			}
 			idempotencyLevel := "UNKNOWN"
 			noSideEffects := false
-			if mo, ok := method.Options().(*descriptorpb.MethodOptions); ok {
+			if mo, ok := desc.Options().(*descriptorpb.MethodOptions); ok {
 				switch mo.GetIdempotencyLevel() {
 				case descriptorpb.MethodOptions_NO_SIDE_EFFECTS:
 					idempotencyLevel = "NO_SIDE_EFFECTS"
@@ -96,28 +149,43 @@ func GenerateConnecpyFile(fd protoreflect.FileDescriptor, conf Config) (*plugin.
 				}
 			}
 			endpointType := "unary"
-			if method.IsStreamingClient() && method.IsStreamingServer() {
+			if desc.IsStreamingClient() && desc.IsStreamingServer() {
 				endpointType = "bidi_stream"
-			} else if method.IsStreamingClient() {
+			} else if desc.IsStreamingClient() {
 				endpointType = "client_stream"
-			} else if method.IsStreamingServer() {
+			} else if desc.IsStreamingServer() {
 				endpointType = "server_stream"
 			} else if idempotencyLevel == "NO_SIDE_EFFECTS" {
 				noSideEffects = true
 			}
+
 			connecpyMethod := &ConnecpyMethod{
-				Package:          packageName,
-				ServiceName:      connecpySvc.FullName,
-				Name:             string(method.Name()),
-				PythonName:       pythonMethodName(string(method.Name()), conf),
-				InputType:        symbolName(method.Input()),
-				OutputType:       symbolName(method.Output()),
-				EndpointType:     endpointType,
-				Stream:           method.IsStreamingClient() || method.IsStreamingServer(),
-				RequestStream:    method.IsStreamingClient(),
-				ResponseStream:   method.IsStreamingServer(),
-				NoSideEffects:    noSideEffects,
-				IdempotencyLevel: idempotencyLevel,
+				Package:               packageName,
+				ServiceName:           connecpySvc.FullName,
+				Name:                  string(desc.Name()),
+				PythonName:            pythonMethodName(string(desc.Name()), conf),
+				InputType:             symbolName(desc.Input()),
+				InputTypeForProtocol:  symbolName(desc.Input()),
+				OutputType:            symbolName(desc.Output()),
+				OutputTypeForProtocol: symbolName(desc.Output()),
+				EndpointType:          endpointType,
+				Stream:                desc.IsStreamingClient() || desc.IsStreamingServer(),
+				RequestStream:         desc.IsStreamingClient(),
+				ResponseStream:        desc.IsStreamingServer(),
+				NoSideEffects:         noSideEffects,
+				IdempotencyLevel:      idempotencyLevel,
+				Deprecated:            methodDeprecated,
+				DeprecationMessage:    deprecationMessage(method.Comments),
+			}
+
+			if rules := httpRulesFromMethod(desc); len(rules) > 0 {
+				primary := rules[0]
+				connecpyMethod.HTTPMethod = primary.Method
+				connecpyMethod.HTTPPath = primary.Path
+				connecpyMethod.BodyField = primary.Body
+				connecpyMethod.ResponseBodyField = primary.ResponseBody
+				connecpyMethod.HTTPPathParams = primary.PathParams
+				connecpyMethod.AdditionalBindings = rules[1:]
 			}
 
 			connecpySvc.Methods = append(connecpySvc.Methods, connecpyMethod)
@@ -125,27 +193,126 @@ func GenerateConnecpyFile(fd protoreflect.FileDescriptor, conf Config) (*plugin.
 		vars.Services = append(vars.Services, connecpySvc)
 	}
 
-	var buf = &bytes.Buffer{}
-	err := ConnecpyTemplate.Execute(buf, vars)
+	return vars, nil
+}
+
+// GenerateStubFile renders the `.pyi` type stub sibling of file and registers it with gen.
+func GenerateStubFile(gen *protogen.Plugin, file *protogen.File, conf Config) error {
+	vars, err := connecpyTemplateVariables(file, conf)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	resp := &plugin.CodeGeneratorResponse_File{
-		Name:    proto.String(strings.TrimSuffix(filename, path.Ext(filename)) + "_connecpy.py"),
-		Content: proto.String(buf.String()),
+	var buf bytes.Buffer
+	if err := ConnecpyStubTemplate.Execute(&buf, vars); err != nil {
+		return err
 	}
 
-	return resp, nil
+	g := gen.NewGeneratedFile(stubFilename(file.Desc.Path()), file.GoImportPath)
+	g.P(buf.String())
+	return nil
+}
+
+// stubFilename returns the `.pyi` stub path for the given proto source path.
+func stubFilename(filename string) string {
+	return strings.TrimSuffix(filename, path.Ext(filename)) + "_connecpy.pyi"
+}
+
+// descriptorSetBase64 serializes fd and its transitive dependencies into a base64-encoded FileDescriptorSet.
+func descriptorSetBase64(fd protoreflect.FileDescriptor) (string, error) {
+	seen := map[string]bool{}
+	var files []*descriptorpb.FileDescriptorProto
+
+	var walk func(f protoreflect.FileDescriptor)
+	walk = func(f protoreflect.FileDescriptor) {
+		if seen[f.Path()] {
+			return
+		}
+		seen[f.Path()] = true
+
+		imports := f.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			walk(imports.Get(i).FileDescriptor)
+		}
+		files = append(files, protodesc.ToFileDescriptorProto(f))
+	}
+	walk(fd)
+
+	data, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: files})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// connecpyFilename returns the generated module path for the given proto source path.
+func connecpyFilename(filename string) string {
+	return strings.TrimSuffix(filename, path.Ext(filename)) + "_connecpy.py"
+}
+
+// openAPIFilename returns the OpenAPI spec path for the given proto source path.
+func openAPIFilename(filename string) string {
+	return strings.TrimSuffix(filename, path.Ext(filename)) + "_openapi.yaml"
+}
+
+// GenerateOpenAPIFile builds the OpenAPI v3 description for file's services and registers it with gen.
+func GenerateOpenAPIFile(gen *protogen.Plugin, file *protogen.File) error {
+	fd := file.Desc
+	bindings := map[string]openapi.HTTPBinding{}
+	for _, svc := range file.Services {
+		for _, method := range svc.Methods {
+			if rules := httpRulesFromMethod(method.Desc); len(rules) > 0 {
+				rule := rules[0]
+				bindings[string(method.Desc.FullName())] = openapi.HTTPBinding{
+					Method: rule.Method,
+					Path:   rule.Path,
+				}
+			}
+		}
+	}
+
+	content, err := openapi.Generate(fd, bindings)
+	if err != nil {
+		return err
+	}
+
+	g := gen.NewGeneratedFile(openAPIFilename(fd.Path()), file.GoImportPath)
+	g.P(content)
+	return nil
+}
+
+// deprecationMessage returns the text after a leading "Deprecated: ..." comment line, or "".
+func deprecationMessage(comments protogen.CommentSet) string {
+	blocks := append([]protogen.Comments{comments.Leading}, comments.LeadingDetached...)
+	for _, block := range blocks {
+		for _, line := range strings.Split(string(block), "\n") {
+			if msg, ok := strings.CutPrefix(strings.TrimSpace(line), "Deprecated:"); ok {
+				return strings.TrimSpace(msg)
+			}
+		}
+	}
+	return ""
+}
+
+// pythonKeywords are the identifiers reserved by Python 3; a method whose
+// computed name collides with one gets a trailing underscore, PEP8-style.
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true,
+	"assert": true, "async": true, "await": true, "break": true, "class": true,
+	"continue": true, "def": true, "del": true, "elif": true, "else": true,
+	"except": true, "finally": true, "for": true, "from": true, "global": true,
+	"if": true, "import": true, "in": true, "is": true, "lambda": true,
+	"nonlocal": true, "not": true, "or": true, "pass": true, "raise": true,
+	"return": true, "try": true, "while": true, "with": true, "yield": true,
 }
 
 func pythonMethodName(name string, conf Config) string {
 	switch conf.Naming {
 	case NamingGoogle:
-		return name
+		return escapePythonKeyword(name)
 	case NamingPEP:
 		if len(name) <= 1 {
-			return strings.ToLower(name)
+			return escapePythonKeyword(strings.ToLower(name))
 		}
 		buf := make([]byte, 0, len(name))
 		buf = append(buf, byte(unicode.ToLower(rune(name[0]))))
@@ -158,12 +325,24 @@ func pythonMethodName(name string, conf Config) string {
 				buf = append(buf, byte(name[i]))
 			}
 		}
-		return string(buf)
+		return escapePythonKeyword(string(buf))
 	default:
 		panic("Unknown naming, this is a bug in protoc-gen-connecpy")
 	}
 }
 
+func escapePythonKeyword(name string) string {
+	if pythonKeywords[name] {
+		return name + "_"
+	}
+	return name
+}
+
+func moduleFromPath(filename string) string {
+	fileNameWithoutSuffix := strings.TrimSuffix(filename, path.Ext(filename))
+	return strings.Join(strings.Split(fileNameWithoutSuffix, "/"), ".")
+}
+
 // https://github.com/grpc/grpc/blob/0dd1b2cad21d89984f9a1b3c6249d649381eeb65/src/compiler/python_generator_helpers.h#L67
 func moduleName(filename string) string {
 	fn, ok := strings.CutSuffix(filename, ".protodevel")
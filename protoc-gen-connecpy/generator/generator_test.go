@@ -4,12 +4,21 @@ import (
 	"strings"
 	"testing"
 
+	"google.golang.org/protobuf/compiler/protogen"
 	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/pluginpb"
 )
 
+func newPlugin(t *testing.T, req *pluginpb.CodeGeneratorRequest) *protogen.Plugin {
+	t.Helper()
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatalf("protogen.Options{}.New() error = %v", err)
+	}
+	return gen
+}
+
 func TestGenerateConnecpyFile(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -22,6 +31,8 @@ func TestGenerateConnecpyFile(t *testing.T) {
 			input: &descriptorpb.FileDescriptorProto{
 				Name:    proto.String("test.proto"),
 				Package: proto.String("test"),
+				Syntax:  proto.String("proto3"),
+				Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/test")},
 				Service: []*descriptorpb.ServiceDescriptorProto{
 					{
 						Name: proto.String("TestService"),
@@ -35,22 +46,19 @@ func TestGenerateConnecpyFile(t *testing.T) {
 					},
 				},
 				MessageType: []*descriptorpb.DescriptorProto{
-					{
-						Name: proto.String("TestRequest"),
-					},
-					{
-						Name: proto.String("TestResponse"),
-					},
+					{Name: proto.String("TestRequest")},
+					{Name: proto.String("TestResponse")},
 				},
 			},
 			wantFile: "test_connecpy.py",
-			wantErr:  false,
 		},
 		{
 			name: "service with multiple methods",
 			input: &descriptorpb.FileDescriptorProto{
 				Name:    proto.String("multi.proto"),
 				Package: proto.String("test"),
+				Syntax:  proto.String("proto3"),
+				Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/test")},
 				Service: []*descriptorpb.ServiceDescriptorProto{
 					{
 						Name: proto.String("MultiService"),
@@ -69,28 +77,21 @@ func TestGenerateConnecpyFile(t *testing.T) {
 					},
 				},
 				MessageType: []*descriptorpb.DescriptorProto{
-					{
-						Name: proto.String("Request1"),
-					},
-					{
-						Name: proto.String("Response1"),
-					},
-					{
-						Name: proto.String("Request2"),
-					},
-					{
-						Name: proto.String("Response2"),
-					},
+					{Name: proto.String("Request1")},
+					{Name: proto.String("Response1")},
+					{Name: proto.String("Request2")},
+					{Name: proto.String("Response2")},
 				},
 			},
 			wantFile: "multi_connecpy.py",
-			wantErr:  false,
 		},
 		{
 			name: "service with streaming methods",
 			input: &descriptorpb.FileDescriptorProto{
 				Name:    proto.String("stream.proto"),
 				Package: proto.String("test"),
+				Syntax:  proto.String("proto3"),
+				Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/test")},
 				Service: []*descriptorpb.ServiceDescriptorProto{
 					{
 						Name: proto.String("StreamService"),
@@ -118,74 +119,73 @@ func TestGenerateConnecpyFile(t *testing.T) {
 					},
 				},
 				MessageType: []*descriptorpb.DescriptorProto{
-					{
-						Name: proto.String("Request"),
-					},
-					{
-						Name: proto.String("Response"),
-					},
+					{Name: proto.String("Request")},
+					{Name: proto.String("Response")},
 				},
 			},
 			wantFile: "stream_connecpy.py",
-			wantErr:  false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			fd, err := protodesc.NewFile(tt.input, nil)
-			if err != nil {
-				t.Fatalf("Failed to create FileDescriptorProto: %v", err)
-				return
+			req := &pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{tt.input.GetName()},
+				ProtoFile:      []*descriptorpb.FileDescriptorProto{tt.input},
 			}
-			got, err := GenerateConnecpyFile(fd, Config{})
+			gen := newPlugin(t, req)
+
+			err := GenerateConnecpyFile(gen, gen.Files[0], Config{})
 			if (err != nil) != tt.wantErr {
-				t.Errorf("GenerateConnecpyFile() error = %v, wantErr %v", err, tt.wantErr)
+				t.Fatalf("GenerateConnecpyFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
 				return
 			}
-			if err == nil {
-				if got.GetName() != tt.wantFile {
-					t.Errorf("GenerateConnecpyFile() got filename = %v, want %v", got.GetName(), tt.wantFile)
-				}
 
-				content := got.GetContent()
-				// Check for base imports
-				if !strings.Contains(content, "from collections.abc import") || !strings.Contains(content, "Iterable") || !strings.Contains(content, "Mapping") {
-					t.Error("Generated code missing required imports")
-				}
+			resp := gen.Response()
+			if resp.GetError() != "" {
+				t.Fatalf("gen.Response() error = %v", resp.GetError())
+			}
+			if len(resp.GetFile()) != 1 {
+				t.Fatalf("gen.Response() returned %d files, want 1", len(resp.GetFile()))
+			}
+			got := resp.GetFile()[0]
+			if got.GetName() != tt.wantFile {
+				t.Errorf("GenerateConnecpyFile() got filename = %v, want %v", got.GetName(), tt.wantFile)
+			}
 
-				// Check for streaming imports based on whether service has streaming methods
-				hasStreaming := false
-				for _, service := range tt.input.GetService() {
-					for _, method := range service.GetMethod() {
-						if method.GetClientStreaming() || method.GetServerStreaming() {
-							hasStreaming = true
-							break
-						}
-					}
+			content := got.GetContent()
+			if !strings.Contains(content, "from collections.abc import") || !strings.Contains(content, "Iterable") || !strings.Contains(content, "Mapping") {
+				t.Error("Generated code missing required imports")
+			}
+
+			hasStreaming := false
+			for _, method := range tt.input.GetService()[0].GetMethod() {
+				if method.GetClientStreaming() || method.GetServerStreaming() {
+					hasStreaming = true
+					break
 				}
+			}
 
-				if hasStreaming {
-					// Should have AsyncIterator and Iterator for streaming methods
-					if !strings.Contains(content, "AsyncIterator") {
-						t.Error("Generated code with streaming methods missing AsyncIterator import")
-					}
-					if !strings.Contains(content, "Iterator") {
-						t.Error("Generated code with streaming methods missing Iterator import")
-					}
-				} else {
-					// Should NOT have AsyncIterator and Iterator for non-streaming methods
-					if strings.Contains(content, "AsyncIterator") {
-						t.Error("Generated code without streaming methods should not have AsyncIterator import")
-					}
-					if strings.Contains(content, " Iterator,") || strings.Contains(content, "Iterator]") {
-						t.Error("Generated code without streaming methods should not have Iterator import")
-					}
+			if hasStreaming {
+				if !strings.Contains(content, "AsyncIterator") {
+					t.Error("Generated code with streaming methods missing AsyncIterator import")
+				}
+				if !strings.Contains(content, "Iterator") {
+					t.Error("Generated code with streaming methods missing Iterator import")
 				}
-				if !strings.Contains(content, "class "+strings.Split(tt.input.GetService()[0].GetName(), ".")[0]) {
-					t.Error("Generated code missing service class")
+			} else {
+				if strings.Contains(content, "AsyncIterator") {
+					t.Error("Generated code without streaming methods should not have AsyncIterator import")
+				}
+				if strings.Contains(content, " Iterator,") || strings.Contains(content, "Iterator]") {
+					t.Error("Generated code without streaming methods should not have Iterator import")
 				}
 			}
+			if !strings.Contains(content, "class "+strings.Split(tt.input.GetService()[0].GetName(), ".")[0]) {
+				t.Error("Generated code missing service class")
+			}
 		})
 	}
 }
@@ -202,17 +202,28 @@ func TestGenerate(t *testing.T) {
 			req: &pluginpb.CodeGeneratorRequest{
 				FileToGenerate: []string{},
 			},
-			wantErr: true,
 		},
 		{
 			name: "valid request",
 			req: &pluginpb.CodeGeneratorRequest{
 				FileToGenerate: []string{"test.proto"},
 				ProtoFile: []*descriptorpb.FileDescriptorProto{
+					{
+						Name:    proto.String("other.proto"),
+						Package: proto.String("otherpackage"),
+						Syntax:  proto.String("proto3"),
+						Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/otherpackage")},
+						MessageType: []*descriptorpb.DescriptorProto{
+							{Name: proto.String("OtherRequest")},
+							{Name: proto.String("OtherResponse")},
+						},
+					},
 					{
 						Name:       proto.String("test.proto"),
 						Package:    proto.String("test"),
+						Syntax:     proto.String("proto3"),
 						Dependency: []string{"other.proto"},
+						Options:    &descriptorpb.FileOptions{GoPackage: proto.String("example.com/test")},
 						Service: []*descriptorpb.ServiceDescriptorProto{
 							{
 								Name: proto.String("TestService"),
@@ -237,51 +248,44 @@ func TestGenerate(t *testing.T) {
 							},
 						},
 						MessageType: []*descriptorpb.DescriptorProto{
-							{
-								Name: proto.String("TestRequest"),
-							},
-							{
-								Name: proto.String("TestResponse"),
-							},
-						},
-					},
-					{
-						Name:    proto.String("other.proto"),
-						Package: proto.String("otherpackage"),
-						MessageType: []*descriptorpb.DescriptorProto{
-							{
-								Name: proto.String("OtherRequest"),
-							},
-							{
-								Name: proto.String("OtherResponse"),
-							},
+							{Name: proto.String("TestRequest")},
+							{Name: proto.String("TestResponse")},
 						},
 					},
 				},
 			},
-			wantErr:     false,
 			wantStrings: []string{"def try_(self"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resp := Generate(tt.req)
-			if tt.wantErr {
-				if resp.GetError() == "" {
-					t.Error("Generate() expected error but got none")
-				}
-			} else {
-				if resp.GetError() != "" {
-					t.Errorf("Generate() unexpected error: %v", resp.GetError())
-				}
-				if len(resp.GetFile()) == 0 {
-					t.Error("Generate() returned no files")
+			gen := newPlugin(t, tt.req)
+
+			err := Generate(gen, Config{Naming: NamingPEP})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			resp := gen.Response()
+			if resp.GetError() != "" {
+				t.Fatalf("Generate() unexpected error: %v", resp.GetError())
+			}
+			if len(tt.req.GetFileToGenerate()) == 0 {
+				if len(resp.GetFile()) != 0 {
+					t.Error("Generate() should produce no files for an empty request")
 				}
-				for _, s := range tt.wantStrings {
-					if !strings.Contains(resp.GetFile()[0].GetContent(), s) {
-						t.Errorf("Generate() missing expected string: %v", s)
-					}
+				return
+			}
+			if len(resp.GetFile()) == 0 {
+				t.Fatal("Generate() returned no files")
+			}
+			for _, s := range tt.wantStrings {
+				if !strings.Contains(resp.GetFile()[0].GetContent(), s) {
+					t.Errorf("Generate() missing expected string: %v", s)
 				}
 			}
 		})
@@ -289,7 +293,6 @@ func TestGenerate(t *testing.T) {
 }
 
 func TestEdition2023Support(t *testing.T) {
-	// Create a request with an Edition 2023 proto file
 	edition2023 := descriptorpb.Edition_EDITION_2023
 
 	req := &pluginpb.CodeGeneratorRequest{
@@ -299,8 +302,9 @@ func TestEdition2023Support(t *testing.T) {
 				Name:    proto.String("test_edition2023.proto"),
 				Package: proto.String("test.edition2023"),
 				Edition: edition2023.Enum(),
-				// Edition 2023 default: field_presence = EXPLICIT
+				Syntax:  proto.String("editions"),
 				Options: &descriptorpb.FileOptions{
+					GoPackage: proto.String("example.com/test/edition2023"),
 					Features: &descriptorpb.FeatureSet{
 						FieldPresence: descriptorpb.FeatureSet_EXPLICIT.Enum(),
 					},
@@ -326,7 +330,6 @@ func TestEdition2023Support(t *testing.T) {
 								Number: proto.Int32(1),
 								Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
 								Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
-								// In Edition 2023, field presence is controlled by features
 							},
 						},
 					},
@@ -346,20 +349,19 @@ func TestEdition2023Support(t *testing.T) {
 		},
 	}
 
-	// Call Generate
-	resp := Generate(req)
+	gen := newPlugin(t, req)
+	if err := Generate(gen, Config{}); err != nil {
+		t.Fatalf("Generate() failed for Edition 2023 proto: %v", err)
+	}
 
-	// Verify no error occurred
+	resp := gen.Response()
 	if resp.GetError() != "" {
 		t.Fatalf("Generate() failed for Edition 2023 proto: %v", resp.GetError())
 	}
 
-	// Verify the generator declared Edition support
 	if resp.GetSupportedFeatures()&uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS) == 0 {
 		t.Error("Generator should declare FEATURE_SUPPORTS_EDITIONS")
 	}
-
-	// Verify minimum and maximum editions are set
 	if resp.GetMinimumEdition() != int32(descriptorpb.Edition_EDITION_PROTO3) {
 		t.Errorf("Expected minimum edition PROTO3, got %v", resp.GetMinimumEdition())
 	}
@@ -367,19 +369,76 @@ func TestEdition2023Support(t *testing.T) {
 		t.Errorf("Expected maximum edition 2023, got %v", resp.GetMaximumEdition())
 	}
 
-	// Verify a file was generated
 	if len(resp.GetFile()) == 0 {
-		t.Error("No files generated for Edition 2023 proto")
-	} else {
-		generatedFile := resp.GetFile()[0]
-		if generatedFile.GetName() != "test_edition2023_connecpy.py" {
-			t.Errorf("Expected filename test_edition2023_connecpy.py, got %v", generatedFile.GetName())
-		}
+		t.Fatal("No files generated for Edition 2023 proto")
+	}
+	generatedFile := resp.GetFile()[0]
+	if generatedFile.GetName() != "test_edition2023_connecpy.py" {
+		t.Errorf("Expected filename test_edition2023_connecpy.py, got %v", generatedFile.GetName())
+	}
+	if !strings.Contains(generatedFile.GetContent(), "class Edition2023Service") {
+		t.Error("Generated code missing Edition2023Service class")
+	}
+}
+
+func TestDeprecatedMethod(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"deprecated.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    proto.String("deprecated.proto"),
+				Package: proto.String("test"),
+				Syntax:  proto.String("proto3"),
+				Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/test")},
+				Service: []*descriptorpb.ServiceDescriptorProto{
+					{
+						Name: proto.String("TestService"),
+						Method: []*descriptorpb.MethodDescriptorProto{
+							{
+								Name:       proto.String("OldMethod"),
+								InputType:  proto.String(".test.TestRequest"),
+								OutputType: proto.String(".test.TestResponse"),
+								Options: &descriptorpb.MethodOptions{
+									Deprecated: proto.Bool(true),
+								},
+							},
+							{
+								Name:       proto.String("NewMethod"),
+								InputType:  proto.String(".test.TestRequest"),
+								OutputType: proto.String(".test.TestResponse"),
+							},
+						},
+					},
+				},
+				MessageType: []*descriptorpb.DescriptorProto{
+					{Name: proto.String("TestRequest")},
+					{Name: proto.String("TestResponse")},
+				},
+			},
+		},
+	}
+
+	gen := newPlugin(t, req)
+	if err := Generate(gen, Config{}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	resp := gen.Response()
+	if resp.GetError() != "" {
+		t.Fatalf("Generate() unexpected error: %v", resp.GetError())
+	}
+	content := resp.GetFile()[0].GetContent()
 
-		// Verify the generated content includes the service
-		content := generatedFile.GetContent()
-		if !strings.Contains(content, "class Edition2023Service") {
-			t.Error("Generated code missing Edition2023Service class")
-		}
+	if !strings.Contains(content, `from typing_extensions import deprecated`) {
+		t.Error("Generated code missing typing_extensions import for deprecated method")
+	}
+	if !strings.Contains(content, `@deprecated("OldMethod is deprecated")`) {
+		t.Error("Generated code missing @deprecated decorator for OldMethod")
+	}
+	if !strings.Contains(content, `warnings.warn(`) || !strings.Contains(content, `DeprecationWarning,`) {
+		t.Error("Generated code missing warnings.warn call for OldMethod")
+	}
+	if strings.Contains(content, `@deprecated("NewMethod is deprecated")`) {
+		t.Error("Generated code should not decorate NewMethod as deprecated")
 	}
 }
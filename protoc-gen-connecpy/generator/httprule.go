@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"regexp"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// HTTPRule describes a single google.api.http binding for a method: the
+// HTTP verb, the URL path template (using "{var}" path variable syntax),
+// and which part of the request/response message, if any, maps to the
+// HTTP body.
+type HTTPRule struct {
+	Method       string
+	Path         string
+	Body         string
+	ResponseBody string
+
+	// PathParams is the list of field names bound from "{var}" segments of
+	// Path, in the order they appear.
+	PathParams []string
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([a-zA-Z0-9_.]+)(?:=[^}]*)?\}`)
+
+// httpRulesFromMethod extracts the google.api.http annotation from a
+// method's options, returning the primary binding followed by any
+// `additional_bindings`. It returns nil if the method does not declare an
+// annotation.
+func httpRulesFromMethod(method protoreflect.MethodDescriptor) []*HTTPRule {
+	mo, ok := method.Options().(*descriptorpb.MethodOptions)
+	if !ok || mo == nil {
+		return nil
+	}
+	if !proto.HasExtension(mo, annotations.E_Http) {
+		return nil
+	}
+	rule, ok := proto.GetExtension(mo, annotations.E_Http).(*annotations.HttpRule)
+	if !ok || rule == nil {
+		return nil
+	}
+
+	primary := newHTTPRule(rule)
+	if primary == nil {
+		return nil
+	}
+
+	rules := []*HTTPRule{primary}
+	for _, additional := range rule.GetAdditionalBindings() {
+		if r := newHTTPRule(additional); r != nil {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+func newHTTPRule(rule *annotations.HttpRule) *HTTPRule {
+	var httpMethod, path string
+	switch pattern := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		httpMethod, path = "GET", pattern.Get
+	case *annotations.HttpRule_Put:
+		httpMethod, path = "PUT", pattern.Put
+	case *annotations.HttpRule_Post:
+		httpMethod, path = "POST", pattern.Post
+	case *annotations.HttpRule_Delete:
+		httpMethod, path = "DELETE", pattern.Delete
+	case *annotations.HttpRule_Patch:
+		httpMethod, path = "PATCH", pattern.Patch
+	case *annotations.HttpRule_Custom:
+		httpMethod, path = pattern.Custom.GetKind(), pattern.Custom.GetPath()
+	default:
+		return nil
+	}
+
+	var params []string
+	for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		params = append(params, m[1])
+	}
+
+	return &HTTPRule{
+		Method:       httpMethod,
+		Path:         path,
+		Body:         rule.GetBody(),
+		ResponseBody: rule.GetResponseBody(),
+		PathParams:   params,
+	}
+}
@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestNewHTTPRule(t *testing.T) {
+	tests := []struct {
+		name string
+		rule *annotations.HttpRule
+		want *HTTPRule
+	}{
+		{
+			name: "get with path variable",
+			rule: &annotations.HttpRule{
+				Pattern: &annotations.HttpRule_Get{Get: "/v1/users/{id}"},
+			},
+			want: &HTTPRule{
+				Method:     "GET",
+				Path:       "/v1/users/{id}",
+				PathParams: []string{"id"},
+			},
+		},
+		{
+			name: "post with body",
+			rule: &annotations.HttpRule{
+				Pattern: &annotations.HttpRule_Post{Post: "/v1/users"},
+				Body:    "*",
+			},
+			want: &HTTPRule{
+				Method: "POST",
+				Path:   "/v1/users",
+				Body:   "*",
+			},
+		},
+		{
+			name: "patch with nested path variable and segment pattern",
+			rule: &annotations.HttpRule{
+				Pattern: &annotations.HttpRule_Patch{Patch: "/v1/{user.id=users/*}"},
+				Body:    "user",
+			},
+			want: &HTTPRule{
+				Method:     "PATCH",
+				Path:       "/v1/{user.id=users/*}",
+				Body:       "user",
+				PathParams: []string{"user.id"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newHTTPRule(tt.rule)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newHTTPRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHTTPRuleResponseBodyAndAdditionalBinding(t *testing.T) {
+	rule := &annotations.HttpRule{
+		Pattern:      &annotations.HttpRule_Get{Get: "/v1/users/{id}"},
+		ResponseBody: "user",
+		AdditionalBindings: []*annotations.HttpRule{
+			{Pattern: &annotations.HttpRule_Get{Get: "/v1/legacy/users/{id}"}},
+		},
+	}
+
+	primary := newHTTPRule(rule)
+	if primary.ResponseBody != "user" {
+		t.Errorf("newHTTPRule() ResponseBody = %q, want %q", primary.ResponseBody, "user")
+	}
+
+	if len(rule.GetAdditionalBindings()) != 1 {
+		t.Fatalf("expected one additional binding in fixture, got %d", len(rule.GetAdditionalBindings()))
+	}
+	additional := newHTTPRule(rule.GetAdditionalBindings()[0])
+	if additional.Path != "/v1/legacy/users/{id}" {
+		t.Errorf("additional binding Path = %q, want %q", additional.Path, "/v1/legacy/users/{id}")
+	}
+}
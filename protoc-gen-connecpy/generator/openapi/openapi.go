@@ -0,0 +1,166 @@
+// Package openapi builds an OpenAPI v3 description of a protobuf service,
+// mirroring the RPC surface that protoc-gen-connecpy generates Python
+// stubs for.
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Generate walks fd's services and methods and returns an OpenAPI v3
+// document, in YAML, describing them. Paths follow the Connect RPC
+// convention of `/{package}.{Service}/{Method}` unless the caller already
+// knows of a `google.api.http` binding, in which case httpBindings supplies
+// the REST-style path for that fully-qualified method name instead.
+func Generate(fd protoreflect.FileDescriptor, httpBindings map[string]HTTPBinding) (string, error) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "openapi: 3.0.3\n")
+	fmt.Fprintf(&b, "info:\n")
+	fmt.Fprintf(&b, "  title: %s\n", string(fd.Package()))
+	fmt.Fprintf(&b, "  version: \"0.0.0\"\n")
+	fmt.Fprintf(&b, "paths:\n")
+
+	schemas := map[string]protoreflect.MessageDescriptor{}
+
+	svcs := fd.Services()
+	for i := 0; i < svcs.Len(); i++ {
+		svc := svcs.Get(i)
+		methods := svc.Methods()
+		for j := 0; j < methods.Len(); j++ {
+			method := methods.Get(j)
+			fqn := string(method.FullName())
+
+			httpMethod := "post"
+			path := fmt.Sprintf("/%s/%s", string(svc.FullName()), string(method.Name()))
+			if binding, ok := httpBindings[fqn]; ok {
+				httpMethod = strings.ToLower(binding.Method)
+				path = binding.Path
+			}
+
+			writePathItem(&b, path, httpMethod, svc, method)
+
+			schemas[string(method.Input().Name())] = method.Input()
+			schemas[string(method.Output().Name())] = method.Output()
+		}
+	}
+
+	fmt.Fprintf(&b, "components:\n")
+	fmt.Fprintf(&b, "  schemas:\n")
+	writeSchemas(&b, schemas)
+	writeErrorSchema(&b)
+
+	return b.String(), nil
+}
+
+// HTTPBinding is the subset of a google.api.http annotation that the
+// OpenAPI generator needs to render a REST-style path instead of the
+// default Connect RPC path.
+type HTTPBinding struct {
+	Method string
+	Path   string
+}
+
+func writePathItem(b *strings.Builder, path, httpMethod string, svc protoreflect.ServiceDescriptor, method protoreflect.MethodDescriptor) {
+	fmt.Fprintf(b, "  %s:\n", path)
+	fmt.Fprintf(b, "    %s:\n", httpMethod)
+	fmt.Fprintf(b, "      operationId: %s.%s\n", string(svc.Name()), string(method.Name()))
+	fmt.Fprintf(b, "      tags: [%s]\n", string(svc.Name()))
+	fmt.Fprintf(b, "      requestBody:\n")
+	fmt.Fprintf(b, "        content:\n")
+	fmt.Fprintf(b, "          application/json:\n")
+	fmt.Fprintf(b, "            schema:\n")
+	fmt.Fprintf(b, "              $ref: '#/components/schemas/%s'\n", string(method.Input().Name()))
+	fmt.Fprintf(b, "      responses:\n")
+	fmt.Fprintf(b, "        '200':\n")
+	fmt.Fprintf(b, "          description: OK\n")
+	fmt.Fprintf(b, "          content:\n")
+	fmt.Fprintf(b, "            application/json:\n")
+	fmt.Fprintf(b, "              schema:\n")
+	fmt.Fprintf(b, "                $ref: '#/components/schemas/%s'\n", string(method.Output().Name()))
+	fmt.Fprintf(b, "        default:\n")
+	fmt.Fprintf(b, "          description: Connect error\n")
+	fmt.Fprintf(b, "          content:\n")
+	fmt.Fprintf(b, "            application/json:\n")
+	fmt.Fprintf(b, "              schema:\n")
+	fmt.Fprintf(b, "                $ref: '#/components/schemas/ConnectError'\n")
+}
+
+func writeSchemas(b *strings.Builder, schemas map[string]protoreflect.MessageDescriptor) {
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		msg := schemas[name]
+		fmt.Fprintf(b, "    %s:\n", name)
+		fmt.Fprintf(b, "      type: object\n")
+		fields := msg.Fields()
+		if fields.Len() == 0 {
+			continue
+		}
+
+		var required []string
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			// Cardinality() already resolves proto2 `required` and the
+			// editions LEGACY_REQUIRED field presence feature to the same
+			// protoreflect.Required value, so this covers both.
+			if field.Cardinality() == protoreflect.Required {
+				required = append(required, string(field.Name()))
+			}
+		}
+
+		fmt.Fprintf(b, "      properties:\n")
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			fmt.Fprintf(b, "        %s:\n", string(field.Name()))
+			fmt.Fprintf(b, "          type: %s\n", jsonSchemaType(field))
+			// HasPresence() reports whether the field distinguishes unset
+			// from its zero value, honoring edition field_presence
+			// features rather than assuming proto3 implicit presence.
+			if field.HasPresence() && field.Cardinality() != protoreflect.Required {
+				fmt.Fprintf(b, "          nullable: true\n")
+			}
+		}
+		if len(required) > 0 {
+			fmt.Fprintf(b, "      required: [%s]\n", strings.Join(required, ", "))
+		}
+	}
+}
+
+func writeErrorSchema(b *strings.Builder) {
+	fmt.Fprintf(b, "    ConnectError:\n")
+	fmt.Fprintf(b, "      type: object\n")
+	fmt.Fprintf(b, "      properties:\n")
+	fmt.Fprintf(b, "        code:\n")
+	fmt.Fprintf(b, "          type: string\n")
+	fmt.Fprintf(b, "        message:\n")
+	fmt.Fprintf(b, "          type: string\n")
+}
+
+func jsonSchemaType(field protoreflect.FieldDescriptor) string {
+	if field.IsList() {
+		return "array"
+	}
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return "boolean"
+	case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Sint32Kind, protoreflect.Sint64Kind, protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		return "integer"
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return "number"
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return "object"
+	default:
+		return "string"
+	}
+}
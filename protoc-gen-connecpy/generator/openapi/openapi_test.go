@@ -0,0 +1,187 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestGenerate(t *testing.T) {
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("TestService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("TestMethod"),
+						InputType:  proto.String(".test.TestRequest"),
+						OutputType: proto.String(".test.TestResponse"),
+					},
+				},
+			},
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("TestRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:   proto.String("id"),
+						Number: proto.Int32(1),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+				},
+			},
+			{Name: proto.String("TestResponse")},
+		},
+	}
+
+	fd, err := protodesc.NewFile(fdProto, nil)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile() error = %v", err)
+	}
+
+	got, err := Generate(fd, nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"openapi: 3.0.3",
+		"/test.TestService/TestMethod:",
+		"TestRequest:",
+		"TestResponse:",
+		"ConnectError:",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Generate() missing %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestGenerateRequiredField(t *testing.T) {
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto2"),
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("TestService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("TestMethod"),
+						InputType:  proto.String(".test.TestRequest"),
+						OutputType: proto.String(".test.TestResponse"),
+					},
+				},
+			},
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("TestRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:   proto.String("id"),
+						Number: proto.Int32(1),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum(),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+					{
+						Name:   proto.String("note"),
+						Number: proto.Int32(2),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+				},
+			},
+			{Name: proto.String("TestResponse")},
+		},
+	}
+
+	fd, err := protodesc.NewFile(fdProto, nil)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile() error = %v", err)
+	}
+
+	got, err := Generate(fd, nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if !strings.Contains(got, "required: [id]") {
+		t.Errorf("Generate() missing required field list, got:\n%s", got)
+	}
+}
+
+func TestGenerateEditionsFieldPresence(t *testing.T) {
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Edition: descriptorpb.Edition_EDITION_2023.Enum(),
+		Syntax:  proto.String("editions"),
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("TestService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("TestMethod"),
+						InputType:  proto.String(".test.TestRequest"),
+						OutputType: proto.String(".test.TestResponse"),
+					},
+				},
+			},
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("TestRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:   proto.String("id"),
+						Number: proto.Int32(1),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Options: &descriptorpb.FieldOptions{
+							Features: &descriptorpb.FeatureSet{
+								FieldPresence: descriptorpb.FeatureSet_EXPLICIT.Enum(),
+							},
+						},
+					},
+					{
+						Name:   proto.String("note"),
+						Number: proto.Int32(2),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Options: &descriptorpb.FieldOptions{
+							Features: &descriptorpb.FeatureSet{
+								FieldPresence: descriptorpb.FeatureSet_IMPLICIT.Enum(),
+							},
+						},
+					},
+				},
+			},
+			{Name: proto.String("TestResponse")},
+		},
+	}
+
+	fd, err := protodesc.NewFile(fdProto, nil)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile() error = %v", err)
+	}
+
+	got, err := Generate(fd, nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if !strings.Contains(got, "id:\n          type: string\n          nullable: true") {
+		t.Errorf("Generate() missing nullable for explicit-presence field, got:\n%s", got)
+	}
+	if strings.Contains(got, "note:\n          type: string\n          nullable: true") {
+		t.Errorf("Generate() should not mark implicit-presence field nullable, got:\n%s", got)
+	}
+}
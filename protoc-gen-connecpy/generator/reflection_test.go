@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestDescriptorSetBase64(t *testing.T) {
+	fdProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{Name: proto.String("TestService")},
+		},
+	}
+
+	fd, err := protodesc.NewFile(fdProto, nil)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile() error = %v", err)
+	}
+
+	got, err := descriptorSetBase64(fd)
+	if err != nil {
+		t.Fatalf("descriptorSetBase64() error = %v", err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(got)
+	if err != nil {
+		t.Fatalf("descriptorSetBase64() did not return valid base64: %v", err)
+	}
+
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &fds); err != nil {
+		t.Fatalf("decoded descriptor set did not unmarshal: %v", err)
+	}
+	if len(fds.GetFile()) != 1 || fds.GetFile()[0].GetName() != "test.proto" {
+		t.Errorf("descriptorSetBase64() = %+v, want file test.proto", fds.GetFile())
+	}
+}
+
+// TestServerReflectionWiring confirms that the generated *Server.__init__
+// sets self._reflection: ConnecpyServer is expected to pick it up and
+// dispatch grpc.reflection.v1.ServerReflection/ServerReflectionInfo to it
+// by convention, the same way it does for self._endpoints/_http_routes.
+func TestServerReflectionWiring(t *testing.T) {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"test.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    proto.String("test.proto"),
+				Package: proto.String("test"),
+				Syntax:  proto.String("proto3"),
+				Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/test")},
+				Service: []*descriptorpb.ServiceDescriptorProto{
+					{Name: proto.String("TestService")},
+				},
+			},
+		},
+	}
+
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatalf("protogen.Options{}.New() error = %v", err)
+	}
+
+	if err := Generate(gen, Config{}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	resp := gen.Response()
+	if resp.GetError() != "" {
+		t.Fatalf("Generate() unexpected error: %v", resp.GetError())
+	}
+	content := resp.GetFile()[0].GetContent()
+
+	if !strings.Contains(content, `self._reflection = ServerReflection(`) {
+		t.Error("Generated server is missing self._reflection wiring")
+	}
+	if !strings.Contains(content, `service_names=["test.TestService"]`) {
+		t.Error("Generated self._reflection missing fully-qualified service name")
+	}
+}
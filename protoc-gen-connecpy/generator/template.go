@@ -6,109 +6,324 @@ type ConnecpyTemplateVariables struct {
 	FileName   string
 	ModuleName string
 	Services   []*ConnecpyService
+	Imports    []ImportStatement
+
+	// OpenAPI is the generated OpenAPI spec path, or "" if not requested.
+	OpenAPI string
+
+	// DescriptorSetBase64 is the base64-encoded FileDescriptorSet embedded for gRPC Server Reflection.
+	DescriptorSetBase64 string
+}
+
+// ImportStatement is a single module import gathered from a method's request/response types.
+type ImportStatement struct {
+	Name     string
+	Alias    string
+	Relative bool
 }
 
 type ConnecpyService struct {
-	Package string
-	Name    string
-	Comment string
-	Methods []*ConnecpyMethod
+	Package  string
+	Name     string
+	FullName string
+	Comment  string
+	Methods  []*ConnecpyMethod
+
+	// Deprecated is true if the service (or its file) is marked deprecated.
+	Deprecated bool
+	// DeprecationMessage is the leading "Deprecated: ..." comment text, or "".
+	DeprecationMessage string
 }
 
 type ConnecpyMethod struct {
-	Package               string
-	ServiceName           string
-	Name                  string
-	Comment               string
-	InputType             string
+	Package     string
+	ServiceName string
+	Name        string
+	PythonName  string
+	Comment     string
+	InputType   string
+	OutputType  string
+	// InputTypeForProtocol and OutputTypeForProtocol are the request/response
+	// types as seen from the Protocol method signature. They're always equal
+	// to InputType/OutputType: a method's request and response are whole
+	// messages, never individually optional, so there's no per-field Editions
+	// presence to apply at this granularity.
 	InputTypeForProtocol  string
-	OutputType            string
 	OutputTypeForProtocol string
 	NoSideEffects         bool
+	IdempotencyLevel      string
+
+	// HTTPMethod and HTTPPath are the method's primary google.api.http binding, or "" if none.
+	HTTPMethod string
+	HTTPPath   string
+	// BodyField and ResponseBodyField name the HTTP body fields, or "" for the whole message.
+	BodyField         string
+	ResponseBodyField string
+	// HTTPPathParams are the field names bound from HTTPPath's "{var}" segments, in order.
+	HTTPPathParams []string
+	// AdditionalBindings are further additional_bindings routes to the same handler.
+	AdditionalBindings []*HTTPRule
+
+	// EndpointType is "unary", "server_stream", "client_stream" or "bidi_stream".
+	EndpointType string
+	// Stream is true if the method streams in either direction.
+	Stream bool
+	// RequestStream is true if the client streams requests.
+	RequestStream bool
+	// ResponseStream is true if the server streams responses.
+	ResponseStream bool
+
+	// Deprecated is true if the method (or its service or file) is marked deprecated.
+	Deprecated bool
+	// DeprecationMessage is the leading "Deprecated: ..." comment text, or "".
+	DeprecationMessage string
 }
 
-// ConnecpyTemplate - Template for connecpy server and client
-var ConnecpyTemplate = template.Must(template.New("ConnecpyTemplate").Parse(`# -*- coding: utf-8 -*-
-# Generated by https://github.com/i2y/connecpy/protoc-gen-connecpy.  DO NOT EDIT!
+// ConnecpyStubTemplate - Template for the `.pyi` type stubs emitted
+// alongside the generated module when stub generation is requested.
+var ConnecpyStubTemplate = template.Must(template.New("ConnecpyStubTemplate").Parse(`# Generated by https://github.com/i2y/connecpy/protoc-gen-connecpy.  DO NOT EDIT!
 # source: {{.FileName}}
 {{if .Services}}
+{{- $anyDeprecated := false}}
+{{- range .Services}}{{if .Deprecated}}{{$anyDeprecated = true}}{{end}}{{range .Methods}}{{if .Deprecated}}{{$anyDeprecated = true}}{{end}}{{end}}{{end}}
 from typing import Optional, Protocol, Union
+from collections.abc import AsyncIterator, Iterable, Iterator, Mapping
 
 import httpx
+{{if $anyDeprecated}}from typing_extensions import deprecated
+{{end}}
+from connecpy.asgi import ConnecpyASGIApplication as ConnecpyASGIApplication
+from connecpy.async_client import AsyncConnecpyClient
+from connecpy.client import ConnecpyClient
+from connecpy.context import ClientContext, ServiceContext
+from connecpy.interceptor import ConnecpyInterceptor
+from connecpy.server import ConnecpyServer
+from connecpy.wsgi import ConnecpyWSGIApplication as ConnecpyWSGIApplication
+
+import {{.ModuleName}}_pb2 as _pb2
+{{- end}}
+{{- range .Services}}
+
+
+{{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+{{end}}class {{.Name}}(Protocol):{{- range .Methods }}
+    {{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+    {{end}}async def {{.PythonName}}(self, req: {{if .RequestStream}}AsyncIterator[{{.InputTypeForProtocol}}]{{else}}{{.InputTypeForProtocol}}{{end}}, ctx: ServiceContext) -> {{if .ResponseStream}}AsyncIterator[{{.OutputTypeForProtocol}}]{{else}}{{.OutputTypeForProtocol}}{{end}}: ...
+{{- end }}
+
+
+class {{.Name}}Server(ConnecpyServer):
+    def __init__(self, *, service: {{.Name}}, server_path_prefix: str = ..., interceptors: Optional[list[ConnecpyInterceptor]] = ...) -> None: ...
+    def serviceName(self) -> str: ...
+
 
+{{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+{{end}}class {{.Name}}Sync(Protocol):{{- range .Methods }}
+    {{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+    {{end}}def {{.PythonName}}(self, req: {{if .RequestStream}}Iterator[{{.InputTypeForProtocol}}]{{else}}{{.InputTypeForProtocol}}{{end}}, ctx: ServiceContext) -> {{if .ResponseStream}}Iterator[{{.OutputTypeForProtocol}}]{{else}}{{.OutputTypeForProtocol}}{{end}}: ...
+{{- end }}
+
+
+class {{.Name}}ServerSync(ConnecpyServer):
+    def __init__(self, *, service: {{.Name}}Sync, server_path_prefix: str = ..., interceptors: Optional[list[ConnecpyInterceptor]] = ...) -> None: ...
+    def serviceName(self) -> str: ...
+
+
+class {{.Name}}Client(ConnecpyClient):{{range .Methods}}
+    {{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+    {{end}}def {{.PythonName}}(
+        self,
+        *,
+        request: {{if .RequestStream}}Iterator[{{.InputTypeForProtocol}}]{{else}}{{.InputTypeForProtocol}}{{end}},
+        ctx: Optional[ClientContext] = ...,
+        server_path_prefix: str = ...,
+        {{if .NoSideEffects}}use_get: bool = ...,
+        {{- end}}
+        **kwargs,
+    ) -> {{if .ResponseStream}}Iterator[{{.OutputTypeForProtocol}}]{{else}}{{.OutputTypeForProtocol}}{{end}}: ...
+{{end}}
+
+class Async{{.Name}}Client(AsyncConnecpyClient):{{range .Methods}}
+    {{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+    {{end}}async def {{.PythonName}}(
+        self,
+        *,
+        request: {{if .RequestStream}}AsyncIterator[{{.InputTypeForProtocol}}]{{else}}{{.InputTypeForProtocol}}{{end}},
+        ctx: Optional[ClientContext] = ...,
+        server_path_prefix: str = ...,
+        session: Union[httpx.AsyncClient, None] = ...,
+        {{if .NoSideEffects}}use_get: bool = ...,
+        {{- end}}
+        **kwargs,
+    ) -> {{if .ResponseStream}}AsyncIterator[{{.OutputTypeForProtocol}}]{{else}}{{.OutputTypeForProtocol}}{{end}}: ...
+{{end}}{{end}}`))
+
+// ConnecpyTemplate - Template for connecpy server and client
+var ConnecpyTemplate = template.Must(template.New("ConnecpyTemplate").Parse(`# -*- coding: utf-8 -*-
+# Generated by https://github.com/i2y/connecpy/protoc-gen-connecpy.  DO NOT EDIT!
+# source: {{.FileName}}
+{{if .OpenAPI}}# openapi: {{.OpenAPI}}
+{{end -}}
+{{if .Services}}
+{{- $anyStreaming := false}}
+{{- $anyDeprecated := false}}
+{{- range .Services}}{{if .Deprecated}}{{$anyDeprecated = true}}{{end}}{{range .Methods}}{{if .Stream}}{{$anyStreaming = true}}{{end}}{{if .Deprecated}}{{$anyDeprecated = true}}{{end}}{{end}}{{end}}
+{{if $anyDeprecated}}import warnings
+{{end}}from typing import Optional, Protocol, Union
+{{if $anyStreaming}}from collections.abc import AsyncIterator, Iterable, Iterator, Mapping
+{{else}}from collections.abc import Iterable, Mapping
+{{end}}
+import httpx
+{{if $anyDeprecated}}from typing_extensions import deprecated
+{{end}}
+from connecpy.asgi import ConnecpyASGIApplication
 from connecpy.async_client import AsyncConnecpyClient
-from connecpy.base import Endpoint
+from connecpy.base import Endpoint, HTTPRoute
 from connecpy.server import ConnecpyServer
 from connecpy.client import ConnecpyClient
 from connecpy.context import ClientContext, ServiceContext
+from connecpy.interceptor import ConnecpyInterceptor
+from connecpy.reflection import ServerReflection
+{{if $anyStreaming}}from connecpy.streaming import StreamingEndpoint{{end}}
+from connecpy.wsgi import ConnecpyWSGIApplication
 
 import {{.ModuleName}}_pb2 as _pb2
+
+# Serialized FileDescriptorSet for this proto and its transitive
+# dependencies, used to answer gRPC Server Reflection queries.
+_FILE_DESCRIPTOR_SET_B64 = "{{.DescriptorSetBase64}}"
 {{- end}}
 {{- range .Services}}
 
 
-class {{.Name}}(Protocol):{{- range .Methods }}
-    async def {{.Name}}(self, req: {{.InputTypeForProtocol}}, ctx: ServiceContext) -> {{.OutputTypeForProtocol}}: ...
+{{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+{{end}}class {{.Name}}(Protocol):{{- range .Methods }}
+    {{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+    {{end}}async def {{.PythonName}}(self, req: {{if .RequestStream}}AsyncIterator[{{.InputTypeForProtocol}}]{{else}}{{.InputTypeForProtocol}}{{end}}, ctx: ServiceContext) -> {{if .ResponseStream}}AsyncIterator[{{.OutputTypeForProtocol}}]{{else}}{{.OutputTypeForProtocol}}{{end}}: ...
 {{- end }}
 
 
 class {{.Name}}Server(ConnecpyServer):
-    def __init__(self, *, service: {{.Name}}, server_path_prefix=""):
-        super().__init__()
+    def __init__(self, *, service: {{.Name}}, server_path_prefix="", interceptors: Optional[list[ConnecpyInterceptor]] = None):
+        super().__init__(interceptors=interceptors)
         self._prefix = f"{server_path_prefix}/{{.Package}}.{{.Name}}"
         self._endpoints = { {{- range .Methods }}
-            "{{.Name}}": Endpoint[{{.InputType}}, {{.OutputType}}](
+            "{{.Name}}": {{if .Stream}}StreamingEndpoint{{else}}Endpoint{{end}}[{{.InputType}}, {{.OutputType}}](
                 service_name="{{.ServiceName}}",
                 name="{{.Name}}",
-                function=getattr(service, "{{.Name}}"),
+                function=getattr(service, "{{.PythonName}}"),
                 input={{.InputType}},
                 output={{.OutputType}},
-                allowed_methods={{if .NoSideEffects}}("GET", "POST"){{else}}("POST",){{end}},
+                {{if .Stream}}endpoint_type="{{.EndpointType}}",
+                {{- else}}allowed_methods={{if .NoSideEffects}}("GET", "POST"){{else}}("POST",){{end}},
+                {{- end}}
             ),{{- end }}
         }
+        self._http_routes = [{{- range .Methods }}{{$methodName := .Name}}{{if .HTTPMethod}}
+            HTTPRoute(
+                method="{{.HTTPMethod}}",
+                path="{{.HTTPPath}}",
+                path_params=[{{range .HTTPPathParams}}"{{.}}", {{end}}],
+                body_field={{if .BodyField}}"{{.BodyField}}"{{else}}None{{end}},
+                endpoint_name="{{.Name}}",
+            ),{{range .AdditionalBindings}}
+            HTTPRoute(
+                method="{{.Method}}",
+                path="{{.Path}}",
+                path_params=[{{range .PathParams}}"{{.}}", {{end}}],
+                body_field={{if .Body}}"{{.Body}}"{{else}}None{{end}},
+                endpoint_name="{{$methodName}}",
+            ),{{end}}{{end}}{{- end }}
+        ]
+        # ConnecpyServer dispatches grpc.reflection.v1.ServerReflection/
+        # ServerReflectionInfo to self._reflection automatically.
+        self._reflection = ServerReflection(
+            service_names=["{{.Package}}.{{.Name}}"],
+            descriptor_set_b64=_FILE_DESCRIPTOR_SET_B64,
+        )
 
     def serviceName(self):
         return "{{.Package}}.{{.Name}}"
 {{- end }}
 
 {{range .Services}}
-class {{.Name}}Sync(Protocol):{{- range .Methods }}
-    def {{.Name}}(self, req: {{.InputTypeForProtocol}}, ctx: ServiceContext) -> {{.OutputTypeForProtocol}}: ...
+{{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+{{end}}class {{.Name}}Sync(Protocol):{{- range .Methods }}
+    {{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+    {{end}}def {{.PythonName}}(self, req: {{if .RequestStream}}Iterator[{{.InputTypeForProtocol}}]{{else}}{{.InputTypeForProtocol}}{{end}}, ctx: ServiceContext) -> {{if .ResponseStream}}Iterator[{{.OutputTypeForProtocol}}]{{else}}{{.OutputTypeForProtocol}}{{end}}: ...
 {{- end }}
 
 
 class {{.Name}}ServerSync(ConnecpyServer):
-    def __init__(self, *, service: {{.Name}}Sync, server_path_prefix=""):
-        super().__init__()
+    def __init__(self, *, service: {{.Name}}Sync, server_path_prefix="", interceptors: Optional[list[ConnecpyInterceptor]] = None):
+        super().__init__(interceptors=interceptors)
         self._prefix = f"{server_path_prefix}/{{.Package}}.{{.Name}}"
         self._endpoints = { {{- range .Methods }}
-            "{{.Name}}": Endpoint[{{.InputType}}, {{.OutputType}}](
+            "{{.Name}}": {{if .Stream}}StreamingEndpoint{{else}}Endpoint{{end}}[{{.InputType}}, {{.OutputType}}](
                 service_name="{{.ServiceName}}",
                 name="{{.Name}}",
-                function=getattr(service, "{{.Name}}"),
+                function=getattr(service, "{{.PythonName}}"),
                 input={{.InputType}},
                 output={{.OutputType}},
-                allowed_methods={{if .NoSideEffects}}("GET", "POST"){{else}}("POST",){{end}},
+                {{if .Stream}}endpoint_type="{{.EndpointType}}",
+                {{- else}}allowed_methods={{if .NoSideEffects}}("GET", "POST"){{else}}("POST",){{end}},
+                {{- end}}
             ),{{- end }}
         }
+        self._http_routes = [{{- range .Methods }}{{$methodName := .Name}}{{if .HTTPMethod}}
+            HTTPRoute(
+                method="{{.HTTPMethod}}",
+                path="{{.HTTPPath}}",
+                path_params=[{{range .HTTPPathParams}}"{{.}}", {{end}}],
+                body_field={{if .BodyField}}"{{.BodyField}}"{{else}}None{{end}},
+                endpoint_name="{{.Name}}",
+            ),{{range .AdditionalBindings}}
+            HTTPRoute(
+                method="{{.Method}}",
+                path="{{.Path}}",
+                path_params=[{{range .PathParams}}"{{.}}", {{end}}],
+                body_field={{if .Body}}"{{.Body}}"{{else}}None{{end}},
+                endpoint_name="{{$methodName}}",
+            ),{{end}}{{end}}{{- end }}
+        ]
+        # ConnecpyServer dispatches grpc.reflection.v1.ServerReflection/
+        # ServerReflectionInfo to self._reflection automatically.
+        self._reflection = ServerReflection(
+            service_names=["{{.Package}}.{{.Name}}"],
+            descriptor_set_b64=_FILE_DESCRIPTOR_SET_B64,
+        )
 
     def serviceName(self):
         return "{{.Package}}.{{.Name}}"
 
 
 class {{.Name}}Client(ConnecpyClient):{{range .Methods}}
-    def {{.Name}}(
+    {{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+    {{end}}def {{.PythonName}}(
         self,
         *,
-        request: {{.InputTypeForProtocol}},
+        request: {{if .RequestStream}}Iterator[{{.InputTypeForProtocol}}]{{else}}{{.InputTypeForProtocol}}{{end}},
         ctx: Optional[ClientContext] = None,
         server_path_prefix: str = "",
         {{if .NoSideEffects}}use_get: bool = False,
         **kwargs,
         {{- else}}**kwargs,{{end}}
-    ) -> {{.OutputTypeForProtocol}}:
-        {{if .NoSideEffects}}method = "GET" if use_get else "POST"{{else}}method = "POST"{{end}}
-        return self._make_request(
+    ) -> {{if .ResponseStream}}Iterator[{{.OutputTypeForProtocol}}]{{else}}{{.OutputTypeForProtocol}}{{end}}:
+        {{if .Deprecated}}warnings.warn(
+            "{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}",
+            DeprecationWarning,
+            stacklevel=2,
+        )
+        {{end}}{{if .NoSideEffects}}method = "GET" if use_get else "POST"{{else}}method = "POST"{{end}}
+        {{if .Stream}}return self._make_streaming_request(
+            url=f"{server_path_prefix}/{{.Package}}.{{.ServiceName}}/{{.Name}}",
+            ctx=ctx,
+            request=request,
+            response_class={{.OutputType}},
+            endpoint_type="{{.EndpointType}}",
+            **kwargs,
+        )
+        {{- else}}return self._make_request(
             url=f"{server_path_prefix}/{{.Package}}.{{.ServiceName}}/{{.Name}}",
             ctx=ctx,
             request=request,
@@ -116,22 +331,38 @@ class {{.Name}}Client(ConnecpyClient):{{range .Methods}}
             method=method,
             **kwargs,
         )
+        {{- end}}
 {{end}}
 
 class Async{{.Name}}Client(AsyncConnecpyClient):{{range .Methods}}
-    async def {{.Name}}(
+    {{if .Deprecated}}@deprecated("{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}")
+    {{end}}async def {{.PythonName}}(
         self,
         *,
-        request: {{.InputTypeForProtocol}},
+        request: {{if .RequestStream}}AsyncIterator[{{.InputTypeForProtocol}}]{{else}}{{.InputTypeForProtocol}}{{end}},
         ctx: Optional[ClientContext] = None,
         server_path_prefix: str = "",
         session: Union[httpx.AsyncClient, None] = None,
         {{if .NoSideEffects}}use_get: bool = False,
         **kwargs,
         {{- else}}**kwargs,{{end}}
-    ) -> {{.OutputTypeForProtocol}}:
-        {{if .NoSideEffects}}method = "GET" if use_get else "POST"{{else}}method = "POST"{{end}}
-        return await self._make_request(
+    ) -> {{if .ResponseStream}}AsyncIterator[{{.OutputTypeForProtocol}}]{{else}}{{.OutputTypeForProtocol}}{{end}}:
+        {{if .Deprecated}}warnings.warn(
+            "{{if .DeprecationMessage}}{{.DeprecationMessage}}{{else}}{{.Name}} is deprecated{{end}}",
+            DeprecationWarning,
+            stacklevel=2,
+        )
+        {{end}}{{if .NoSideEffects}}method = "GET" if use_get else "POST"{{else}}method = "POST"{{end}}
+        {{if .Stream}}return self._make_streaming_request(
+            url=f"{server_path_prefix}/{{.Package}}.{{.ServiceName}}/{{.Name}}",
+            ctx=ctx,
+            request=request,
+            response_class={{.OutputType}},
+            endpoint_type="{{.EndpointType}}",
+            session=session,
+            **kwargs,
+        )
+        {{- else}}return await self._make_request(
             url=f"{server_path_prefix}/{{.Package}}.{{.ServiceName}}/{{.Name}}",
             ctx=ctx,
             request=request,
@@ -140,4 +371,5 @@ class Async{{.Name}}Client(AsyncConnecpyClient):{{range .Methods}}
             session=session,
             **kwargs,
         )
+        {{- end}}
 {{end}}{{end}}`))
@@ -6,6 +6,51 @@ import (
 	"testing"
 )
 
+func TestConnecpyStubTemplate(t *testing.T) {
+	vars := ConnecpyTemplateVariables{
+		FileName:   "test.proto",
+		ModuleName: "test",
+		Services: []*ConnecpyService{
+			{
+				Package: "test",
+				Name:    "TestService",
+				Methods: []*ConnecpyMethod{
+					{
+						Package:               "test",
+						ServiceName:           "TestService",
+						Name:                  "TestMethod",
+						PythonName:            "TestMethod",
+						InputType:             "_pb2.TestRequest",
+						OutputType:            "_pb2.TestResponse",
+						InputTypeForProtocol:  "_pb2.TestRequest",
+						OutputTypeForProtocol: "_pb2.TestResponse",
+					},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := ConnecpyStubTemplate.Execute(&buf, vars); err != nil {
+		t.Fatalf("Template execution failed: %v", err)
+	}
+
+	result := buf.String()
+	for _, want := range []string{
+		"class TestService(Protocol):",
+		"async def TestMethod(self, req: _pb2.TestRequest, ctx: ServiceContext) -> _pb2.TestResponse: ...",
+		"class TestServiceServer(ConnecpyServer):",
+		"class TestServiceClient(ConnecpyClient):",
+		"class AsyncTestServiceClient(AsyncConnecpyClient):",
+		"from connecpy.asgi import ConnecpyASGIApplication as ConnecpyASGIApplication",
+		"from connecpy.wsgi import ConnecpyWSGIApplication as ConnecpyWSGIApplication",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Generated stub missing expected content: %q, got: %q", want, result)
+		}
+	}
+}
+
 func TestConnecpyTemplate(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -23,13 +68,15 @@ func TestConnecpyTemplate(t *testing.T) {
 						Name:    "TestService",
 						Methods: []*ConnecpyMethod{
 							{
-								Package:       "test",
-								ServiceName:   "TestService",
-								Name:          "TestMethod",
-								PythonName:    "TestMethod",
-								InputType:     "_pb2.TestRequest",
-								OutputType:    "_pb2.TestResponse",
-								NoSideEffects: false,
+								Package:               "test",
+								ServiceName:           "TestService",
+								Name:                  "TestMethod",
+								PythonName:            "TestMethod",
+								InputType:             "_pb2.TestRequest",
+								OutputType:            "_pb2.TestResponse",
+								InputTypeForProtocol:  "_pb2.TestRequest",
+								OutputTypeForProtocol: "_pb2.TestResponse",
+								NoSideEffects:         false,
 							},
 						},
 					},
@@ -38,8 +85,13 @@ func TestConnecpyTemplate(t *testing.T) {
 			contains: []string{
 				"from collections.abc import Iterable, Mapping",
 				"class TestService(Protocol):",
-				"class TestServiceASGIApplication(ConnecpyASGIApplication):",
+				"async def TestMethod(self, req: _pb2.TestRequest, ctx: ServiceContext) -> _pb2.TestResponse: ...",
+				"class TestServiceServer(ConnecpyServer):",
 				"def TestMethod",
+				"interceptors: Optional[list[ConnecpyInterceptor]] = None",
+				"super().__init__(interceptors=interceptors)",
+				"from connecpy.asgi import ConnecpyASGIApplication",
+				"from connecpy.wsgi import ConnecpyWSGIApplication",
 			},
 		},
 		{
@@ -69,6 +121,70 @@ func TestConnecpyTemplate(t *testing.T) {
 				"use_get: bool = False",
 			},
 		},
+		{
+			name: "service with streaming methods",
+			vars: ConnecpyTemplateVariables{
+				FileName:   "stream.proto",
+				ModuleName: "stream",
+				Services: []*ConnecpyService{
+					{
+						Package: "test",
+						Name:    "StreamService",
+						Methods: []*ConnecpyMethod{
+							{
+								Package:               "test",
+								ServiceName:           "StreamService",
+								Name:                  "ServerStream",
+								PythonName:            "ServerStream",
+								InputType:             "_pb2.Request",
+								OutputType:            "_pb2.Response",
+								InputTypeForProtocol:  "_pb2.Request",
+								OutputTypeForProtocol: "_pb2.Response",
+								EndpointType:          "server_stream",
+								Stream:                true,
+								ResponseStream:        true,
+							},
+							{
+								Package:               "test",
+								ServiceName:           "StreamService",
+								Name:                  "ClientStream",
+								PythonName:            "ClientStream",
+								InputType:             "_pb2.Request",
+								OutputType:            "_pb2.Response",
+								InputTypeForProtocol:  "_pb2.Request",
+								OutputTypeForProtocol: "_pb2.Response",
+								EndpointType:          "client_stream",
+								Stream:                true,
+								RequestStream:         true,
+							},
+							{
+								Package:               "test",
+								ServiceName:           "StreamService",
+								Name:                  "BidiStream",
+								PythonName:            "BidiStream",
+								InputType:             "_pb2.Request",
+								OutputType:            "_pb2.Response",
+								InputTypeForProtocol:  "_pb2.Request",
+								OutputTypeForProtocol: "_pb2.Response",
+								EndpointType:          "bidi_stream",
+								Stream:                true,
+								RequestStream:         true,
+								ResponseStream:        true,
+							},
+						},
+					},
+				},
+			},
+			contains: []string{
+				"from collections.abc import AsyncIterator, Iterable, Iterator, Mapping",
+				"from connecpy.streaming import StreamingEndpoint",
+				"async def ServerStream(self, req: _pb2.Request, ctx: ServiceContext) -> AsyncIterator[_pb2.Response]: ...",
+				"async def ClientStream(self, req: AsyncIterator[_pb2.Request], ctx: ServiceContext) -> _pb2.Response: ...",
+				"async def BidiStream(self, req: AsyncIterator[_pb2.Request], ctx: ServiceContext) -> AsyncIterator[_pb2.Response]: ...",
+				`StreamingEndpoint[_pb2.Request, _pb2.Response](`,
+				`endpoint_type="server_stream"`,
+			},
+		},
 	}
 
 	for _, tt := range tests {
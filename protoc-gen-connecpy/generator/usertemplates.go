@@ -0,0 +1,166 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// userTemplateFuncs is the funcmap made available to every user template,
+// in addition to the usual text/template builtins.
+var userTemplateFuncs = template.FuncMap{
+	"snake":    snakeCase,
+	"camel":    camelCase,
+	"pyEscape": pyEscape,
+	"lastPart": lastPart,
+}
+
+// outputDirective matches a template's `{{/* output: ... */}}` front
+// matter. The captured body is itself executed as a template against the
+// same variables to produce the output path, e.g.
+// `{{/* output: {{.ModuleName}}_custom.py */}}`.
+var outputDirective = regexp.MustCompile(`(?m)^\s*\{\{/\*\s*output:\s*(.*?)\s*\*/\}\}\s*\n?`)
+
+// userTemplate is one parsed `.tmpl` file: its body, and the (optional)
+// template describing where to write its rendered output.
+type userTemplate struct {
+	name   string
+	body   *template.Template
+	output *template.Template // nil if the file had no `output:` directive
+}
+
+// TemplateSet is a named collection of user-supplied templates, keyed by
+// base filename (without the .tmpl extension).
+type TemplateSet map[string]*userTemplate
+
+// RenderedTemplateFile is one output produced by executing a user template
+// against a ConnecpyTemplateVariables value.
+type RenderedTemplateFile struct {
+	Name    string
+	Content string
+}
+
+// LoadTemplateSet reads every `*.tmpl` file in dir and parses it with the
+// shared funcmap, so it can be executed against the same
+// ConnecpyTemplateVariables the built-in ConnecpyTemplate uses.
+func LoadTemplateSet(dir string) (TemplateSet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading template dir %q: %w", dir, err)
+	}
+
+	set := TemplateSet{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tmpl") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading template %q: %w", entry.Name(), err)
+		}
+
+		src := string(data)
+		var output *template.Template
+		if m := outputDirective.FindStringSubmatchIndex(src); m != nil {
+			outputSrc := src[m[2]:m[3]]
+			output, err = template.New(name + ":output").Funcs(userTemplateFuncs).Parse(outputSrc)
+			if err != nil {
+				return nil, fmt.Errorf("parsing output directive in %q: %w", entry.Name(), err)
+			}
+			src = src[:m[0]] + src[m[1]:]
+		}
+
+		body, err := template.New(name).Funcs(userTemplateFuncs).Parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template %q: %w", entry.Name(), err)
+		}
+
+		set[name] = &userTemplate{name: name, body: body, output: output}
+	}
+	return set, nil
+}
+
+// Render executes every template in the set against vars and returns the
+// resulting files. If conf.SingleFile is set, all outputs are concatenated
+// into one file instead of being returned separately.
+func (set TemplateSet) Render(vars ConnecpyTemplateVariables, conf Config) ([]RenderedTemplateFile, error) {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []RenderedTemplateFile
+	for _, name := range names {
+		tmpl := set[name]
+
+		outputName := fmt.Sprintf("%s_%s.py", vars.ModuleName, name)
+		if tmpl.output != nil {
+			var buf strings.Builder
+			if err := tmpl.output.Execute(&buf, vars); err != nil {
+				return nil, fmt.Errorf("resolving output path for template %q: %w", name, err)
+			}
+			outputName = buf.String()
+		}
+
+		var buf strings.Builder
+		if err := tmpl.body.Execute(&buf, vars); err != nil {
+			return nil, fmt.Errorf("executing template %q: %w", name, err)
+		}
+
+		files = append(files, RenderedTemplateFile{Name: outputName, Content: buf.String()})
+	}
+
+	if conf.SingleFile && len(files) > 0 {
+		var combined strings.Builder
+		for i, f := range files {
+			if i > 0 {
+				combined.WriteString("\n\n")
+			}
+			combined.WriteString(f.Content)
+		}
+		return []RenderedTemplateFile{{Name: files[0].Name, Content: combined.String()}}, nil
+	}
+
+	return files, nil
+}
+
+func snakeCase(s string) string {
+	if len(s) <= 1 {
+		return strings.ToLower(s)
+	}
+	buf := make([]byte, 0, len(s)+4)
+	buf = append(buf, byte(unicode.ToLower(rune(s[0]))))
+	for i := 1; i < len(s); i++ {
+		if unicode.IsUpper(rune(s[i])) {
+			buf = append(buf, '_')
+			buf = append(buf, byte(unicode.ToLower(rune(s[i]))))
+		} else {
+			buf = append(buf, s[i])
+		}
+	}
+	return string(buf)
+}
+
+func camelCase(s string) string {
+	parts := strings.Split(s, "_")
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		parts[i] = strings.ToUpper(p[:1]) + p[1:]
+	}
+	return strings.Join(parts, "")
+}
+
+func pyEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return s
+}
@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateSetRender(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "sanic.tmpl", `{{/* output: {{.ModuleName}}_sanic.py */}}
+# Sanic adapter for {{snake "GetUser"}}
+`)
+
+	set, err := LoadTemplateSet(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplateSet() error = %v", err)
+	}
+	if len(set) != 1 {
+		t.Fatalf("LoadTemplateSet() loaded %d templates, want 1", len(set))
+	}
+
+	vars := ConnecpyTemplateVariables{ModuleName: "test"}
+	files, err := set.Render(vars, Config{})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Render() returned %d files, want 1", len(files))
+	}
+	if files[0].Name != "test_sanic.py" {
+		t.Errorf("Render() output name = %q, want %q", files[0].Name, "test_sanic.py")
+	}
+	if want := "# Sanic adapter for get_user\n"; files[0].Content != want {
+		t.Errorf("Render() content = %q, want %q", files[0].Content, want)
+	}
+}
+
+func TestTemplateSetRenderSingleFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.tmpl", "part a\n")
+	writeFile(t, dir, "b.tmpl", "part b\n")
+
+	set, err := LoadTemplateSet(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplateSet() error = %v", err)
+	}
+
+	files, err := set.Render(ConnecpyTemplateVariables{ModuleName: "test"}, Config{SingleFile: true})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Render() with SingleFile returned %d files, want 1", len(files))
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("writing %q: %v", name, err)
+	}
+}